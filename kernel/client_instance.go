@@ -0,0 +1,143 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kernel
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/apache/rocketmq-client-go/utils"
+)
+
+// ClientOption identifies which MQClientInstance a producer or consumer
+// should share: instances are keyed by nameserver address plus unit name,
+// mirroring the Java client's grouping of clients under one JVM. A
+// producer and a consumer with the same NameServerAddr/UnitName share one
+// instance, so per-role settings like the VIP channel must not live here
+// — see MQClientInstance.FindBrokerAddressInPublish.
+type ClientOption struct {
+	NameServerAddr string
+	UnitName       string
+}
+
+// MQClientInstance is a logical RocketMQ client: one route table, broker
+// version map and nameserver resolver shared by every producer and
+// consumer constructed with the same ClientOption. Keeping this state off
+// package-level variables lets a single process run multiple isolated
+// logical clients, e.g. in tests or multi-tenant applications.
+type MQClientInstance struct {
+	// ClientID is "ip@pid[@unitName]", matching the Java client's clientId.
+	ClientID string
+
+	brokerAddressesMap sync.Map // brokerName -> map[int64]string
+	brokerVersionMap   sync.Map // brokerName -> map[string]int
+	publishInfoMap     sync.Map // topic -> *TopicPublishInfo
+	routeDataMap       sync.Map // topic -> *topicRouteData
+	lockNamesrv        sync.Mutex
+
+	vipUnavailableMap sync.Map // brokerName -> unix nano cooldown expiry
+
+	resolver     *NameServerResolver
+	routeManager *RouteManager
+}
+
+var (
+	clientInstanceMutex sync.Mutex
+	clientInstanceTable = make(map[string]*MQClientInstance)
+)
+
+// ClientID builds the "ip@pid[@unitName]" identity used to label an
+// MQClientInstance, mirroring the Java client's clientId.
+func ClientID(opt ClientOption) string {
+	ip := utils.LocalIP()
+	id := fmt.Sprintf("%d.%d.%d.%d@%d", ip[0], ip[1], ip[2], ip[3], os.Getpid())
+	if opt.UnitName != "" {
+		id += "@" + opt.UnitName
+	}
+	return id
+}
+
+// GetOrCreateInstance returns the MQClientInstance shared by every caller
+// using the same nameserver address and unit name, creating it on first
+// use.
+func GetOrCreateInstance(opt ClientOption) *MQClientInstance {
+	key := opt.NameServerAddr + "@" + opt.UnitName
+
+	clientInstanceMutex.Lock()
+	defer clientInstanceMutex.Unlock()
+
+	if instance, exist := clientInstanceTable[key]; exist {
+		return instance
+	}
+
+	instance := &MQClientInstance{
+		ClientID: ClientID(opt),
+		resolver: NewNameServerResolver(opt.NameServerAddr),
+	}
+	instance.routeManager = NewRouteManager(instance)
+
+	clientInstanceTable[key] = instance
+	return instance
+}
+
+// defaultClientInstance backs the package-level helper functions kept for
+// callers that have not migrated to an explicit MQClientInstance yet.
+var defaultClientInstance = GetOrCreateInstance(ClientOption{NameServerAddr: os.Getenv(envNameServerAddr)})
+
+// UpdateTopicRouteInfo updates the default MQClientInstance's route table
+// for topic. Deprecated: use MQClientInstance.UpdateTopicRouteInfo.
+func UpdateTopicRouteInfo(topic string) {
+	defaultClientInstance.UpdateTopicRouteInfo(topic)
+}
+
+// FindBrokerAddressInPublish looks up a publish-capable broker address on
+// the default MQClientInstance. Deprecated: use
+// MQClientInstance.FindBrokerAddressInPublish.
+func FindBrokerAddressInPublish(brokerName string, vipChannelEnabled bool) string {
+	return defaultClientInstance.FindBrokerAddressInPublish(brokerName, vipChannelEnabled)
+}
+
+// FindBrokerAddressInSubscribe looks up a subscribe-capable broker address
+// on the default MQClientInstance. Deprecated: use
+// MQClientInstance.FindBrokerAddressInSubscribe.
+func FindBrokerAddressInSubscribe(brokerName string, brokerId int64, onlyThisBroker bool) *FindBrokerResult {
+	return defaultClientInstance.FindBrokerAddressInSubscribe(brokerName, brokerId, onlyThisBroker)
+}
+
+// FetchSubscribeMessageQueues fetches a topic's readable queues using the
+// default MQClientInstance. Deprecated: use
+// MQClientInstance.FetchSubscribeMessageQueues.
+func FetchSubscribeMessageQueues(topic string) ([]*MessageQueue, error) {
+	return defaultClientInstance.FetchSubscribeMessageQueues(topic)
+}
+
+// Resolver returns the instance's NameServerResolver, so callers can
+// configure dynamic nameserver discovery (SetHTTPEndpoint, SetDomain,
+// SetPollInterval) and start or stop its background refresh goroutine.
+func (c *MQClientInstance) Resolver() *NameServerResolver {
+	return c.resolver
+}
+
+// RouteManager returns the instance's RouteManager, so producers and
+// consumers can register the topics they use (RegisterTopic,
+// UnregisterTopic) and start or stop the periodic route refresh (Start,
+// Stop) instead of calling UpdateTopicRouteInfo directly.
+func (c *MQClientInstance) RouteManager() *RouteManager {
+	return c.routeManager
+}
@@ -0,0 +1,85 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kernel
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetOrCreateInstance_SharesInstanceForSameKey(t *testing.T) {
+	opt := ClientOption{NameServerAddr: "10.0.0.1:9876", UnitName: "unit-a"}
+
+	first := GetOrCreateInstance(opt)
+	second := GetOrCreateInstance(opt)
+
+	if first != second {
+		t.Errorf("expected callers with the same NameServerAddr/UnitName to share one MQClientInstance")
+	}
+}
+
+func TestGetOrCreateInstance_IsolatesDifferentKeys(t *testing.T) {
+	byAddr := GetOrCreateInstance(ClientOption{NameServerAddr: "10.0.0.2:9876"})
+	byUnit := GetOrCreateInstance(ClientOption{NameServerAddr: "10.0.0.2:9876", UnitName: "unit-b"})
+
+	if byAddr == byUnit {
+		t.Errorf("expected different NameServerAddr/UnitName combinations to get separate MQClientInstances")
+	}
+}
+
+func TestClientID_Format(t *testing.T) {
+	id := ClientID(ClientOption{NameServerAddr: "10.0.0.3:9876", UnitName: "unit-c"})
+
+	if !strings.HasSuffix(id, "@unit-c") {
+		t.Errorf("expected ClientID to end with the unit name, got %q", id)
+	}
+	if strings.Count(id, "@") != 2 {
+		t.Errorf("expected ClientID to have the form ip@pid@unitName, got %q", id)
+	}
+
+	idWithoutUnit := ClientID(ClientOption{NameServerAddr: "10.0.0.3:9876"})
+	if strings.Count(idWithoutUnit, "@") != 1 {
+		t.Errorf("expected ClientID to drop the trailing segment when UnitName is empty, got %q", idWithoutUnit)
+	}
+}
+
+// TestFindBrokerAddressInPublish_ProducerAndConsumerCanDifferOnSharedInstance
+// guards against the instance-sharing collision: a producer and a consumer
+// with the same ClientOption share one MQClientInstance, so the VIP default
+// must come from the caller, not from state on the instance.
+func TestFindBrokerAddressInPublish_ProducerAndConsumerCanDifferOnSharedInstance(t *testing.T) {
+	opt := ClientOption{NameServerAddr: "10.0.0.4:9876"}
+	producerInstance := GetOrCreateInstance(opt)
+	consumerInstance := GetOrCreateInstance(opt)
+
+	if producerInstance != consumerInstance {
+		t.Fatalf("expected the producer and consumer to share one MQClientInstance")
+	}
+
+	producerInstance.brokerAddressesMap.Store("broker-a", map[int64]string{MasterId: "10.0.0.1:10911"})
+
+	producerAddr := producerInstance.FindBrokerAddressInPublish("broker-a", DefaultProducerVipChannelEnabled)
+	if producerAddr != "10.0.0.1:10909" {
+		t.Errorf("expected the producer's default to use the VIP address, got %q", producerAddr)
+	}
+
+	consumerAddr := consumerInstance.FindBrokerAddressInPublish("broker-a", DefaultConsumerVipChannelEnabled)
+	if consumerAddr != "10.0.0.1:10911" {
+		t.Errorf("expected the consumer's default to use the normal address, got %q", consumerAddr)
+	}
+}
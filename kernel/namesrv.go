@@ -0,0 +1,333 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kernel
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/apache/rocketmq-client-go/rlog"
+)
+
+const (
+	// envNameServerAddr is the environment variable holding a comma
+	// separated list of nameserver addresses, mirroring the Java client's
+	// NAMESRV_ADDR.
+	envNameServerAddr = "NAMESRV_ADDR"
+
+	defaultNsAddrPollInterval = 30 * time.Second
+	nsAddrBaseBackoff         = time.Second
+	nsAddrMaxBackoff          = time.Minute
+	nsAddrMaxFailures         = 6
+)
+
+// nsAddrHealth tracks the liveness of a single candidate nameserver
+// address so a downed nameserver doesn't keep getting picked.
+type nsAddrHealth struct {
+	addr         string
+	failureCount int32
+	bannedUntil  int64 // unix nano, zero means not banned
+}
+
+func (h *nsAddrHealth) isBanned(now time.Time) bool {
+	until := atomic.LoadInt64(&h.bannedUntil)
+	return until != 0 && now.UnixNano() < until
+}
+
+func (h *nsAddrHealth) onSuccess() {
+	atomic.StoreInt32(&h.failureCount, 0)
+	atomic.StoreInt64(&h.bannedUntil, 0)
+}
+
+func (h *nsAddrHealth) onFailure() {
+	count := atomic.AddInt32(&h.failureCount, 1)
+	if count > nsAddrMaxFailures {
+		count = nsAddrMaxFailures
+	}
+	backoff := nsAddrBaseBackoff * time.Duration(1<<uint(count-1))
+	if backoff > nsAddrMaxBackoff {
+		backoff = nsAddrMaxBackoff
+	}
+	atomic.StoreInt64(&h.bannedUntil, time.Now().Add(backoff).UnixNano())
+}
+
+// NameServerResolver keeps track of the candidate nameserver addresses a
+// client may talk to. Addresses can be seeded statically (NAMESRV_ADDR),
+// discovered by polling an HTTP endpoint (the Java client's WS_ADDR) or
+// resolved from a DNS SRV record, and are exposed to callers ordered by
+// health so a downed nameserver stops being picked.
+type NameServerResolver struct {
+	mutex sync.RWMutex
+	addrs []*nsAddrHealth
+	next  uint32
+
+	httpEndpoint string
+	srvName      string
+	pollInterval time.Duration
+
+	stopOnce sync.Once
+	stopChan chan struct{}
+}
+
+// NewNameServerResolver builds a resolver seeded with a comma separated
+// list of "host:port" addresses, e.g. the value of NAMESRV_ADDR.
+func NewNameServerResolver(staticAddrs string) *NameServerResolver {
+	r := &NameServerResolver{
+		pollInterval: defaultNsAddrPollInterval,
+		stopChan:     make(chan struct{}),
+	}
+	r.replaceAddrs(splitNsAddrs(staticAddrs))
+	return r
+}
+
+func splitNsAddrs(addrs string) []string {
+	if addrs == "" {
+		return nil
+	}
+	var result []string
+	for _, addr := range strings.Split(addrs, ",") {
+		addr = strings.TrimSpace(addr)
+		if addr != "" {
+			result = append(result, addr)
+		}
+	}
+	return result
+}
+
+// SetHTTPEndpoint configures the URL that is polled for the current
+// nameserver address list, mirroring the Java client's WS_ADDR.
+func (r *NameServerResolver) SetHTTPEndpoint(url string) {
+	r.mutex.Lock()
+	r.httpEndpoint = url
+	r.mutex.Unlock()
+}
+
+// SetDomain configures a DNS SRV service name to resolve candidate
+// nameserver addresses from, e.g. "_rocketmq._tcp.example.com".
+func (r *NameServerResolver) SetDomain(srvName string) {
+	r.mutex.Lock()
+	r.srvName = srvName
+	r.mutex.Unlock()
+}
+
+// SetPollInterval overrides the default interval used by Start to refresh
+// the address list from the HTTP endpoint and/or DNS SRV record. Values
+// less than or equal to zero are ignored and leave the current interval
+// unchanged, since time.NewTicker panics on a non-positive duration.
+func (r *NameServerResolver) SetPollInterval(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	r.mutex.Lock()
+	r.pollInterval = interval
+	r.mutex.Unlock()
+}
+
+// Start launches a background goroutine that periodically refreshes the
+// address list from the configured HTTP endpoint and/or DNS SRV record.
+// The goroutine is started even if neither is configured; each tick then
+// leaves the list unchanged, and an endpoint or domain set later is used
+// from the next tick on.
+func (r *NameServerResolver) Start() {
+	r.mutex.RLock()
+	interval := r.pollInterval
+	r.mutex.RUnlock()
+
+	if interval <= 0 {
+		interval = defaultNsAddrPollInterval
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				r.refresh()
+			case <-r.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// Stop terminates the background refresh goroutine started by Start.
+func (r *NameServerResolver) Stop() {
+	r.stopOnce.Do(func() {
+		close(r.stopChan)
+	})
+}
+
+func (r *NameServerResolver) refresh() {
+	var discovered []string
+
+	r.mutex.RLock()
+	endpoint := r.httpEndpoint
+	srvName := r.srvName
+	r.mutex.RUnlock()
+
+	if endpoint != "" {
+		addrs, err := fetchNameServerAddrsFromHTTP(endpoint)
+		if err != nil {
+			rlog.Warnf("fetch nameserver address list from %s error: %s", endpoint, err)
+		} else {
+			discovered = append(discovered, addrs...)
+		}
+	}
+
+	if srvName != "" {
+		addrs, err := resolveNameServerAddrsFromSRV(srvName)
+		if err != nil {
+			rlog.Warnf("resolve nameserver address list from SRV %s error: %s", srvName, err)
+		} else {
+			discovered = append(discovered, addrs...)
+		}
+	}
+
+	if len(discovered) == 0 {
+		return
+	}
+
+	r.replaceAddrs(discovered)
+}
+
+// replaceAddrs swaps in a new address list, preserving health state for
+// addresses that are still present and logging membership changes.
+func (r *NameServerResolver) replaceAddrs(addrs []string) {
+	sort.Strings(addrs)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	existing := make(map[string]*nsAddrHealth, len(r.addrs))
+	for _, h := range r.addrs {
+		existing[h.addr] = h
+	}
+
+	next := make(map[string]bool, len(addrs))
+	updated := make([]*nsAddrHealth, 0, len(addrs))
+	for _, addr := range addrs {
+		next[addr] = true
+		if h, ok := existing[addr]; ok {
+			updated = append(updated, h)
+		} else {
+			updated = append(updated, &nsAddrHealth{addr: addr})
+			rlog.Infof("nameserver address %s joined", addr)
+		}
+	}
+
+	for addr := range existing {
+		if !next[addr] {
+			rlog.Infof("nameserver address %s left", addr)
+		}
+	}
+
+	r.addrs = updated
+}
+
+// candidates returns the known addresses ordered starting from the next
+// round-robin position, with healthy (non-banned) addresses preferred
+// over banned ones.
+func (r *NameServerResolver) candidates() []string {
+	r.mutex.RLock()
+	addrs := make([]*nsAddrHealth, len(r.addrs))
+	copy(addrs, r.addrs)
+	r.mutex.RUnlock()
+
+	if len(addrs) == 0 {
+		return nil
+	}
+
+	start := int(atomic.AddUint32(&r.next, 1)) % len(addrs)
+	rotated := make([]*nsAddrHealth, len(addrs))
+	for i := range addrs {
+		rotated[i] = addrs[(start+i)%len(addrs)]
+	}
+
+	now := time.Now()
+	healthy := make([]string, 0, len(rotated))
+	banned := make([]string, 0, len(rotated))
+	for _, h := range rotated {
+		if h.isBanned(now) {
+			banned = append(banned, h.addr)
+		} else {
+			healthy = append(healthy, h.addr)
+		}
+	}
+
+	return append(healthy, banned...)
+}
+
+func (r *NameServerResolver) find(addr string) *nsAddrHealth {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	for _, h := range r.addrs {
+		if h.addr == addr {
+			return h
+		}
+	}
+	return nil
+}
+
+func (r *NameServerResolver) markSuccess(addr string) {
+	if h := r.find(addr); h != nil {
+		h.onSuccess()
+	}
+}
+
+func (r *NameServerResolver) markFailure(addr string) {
+	if h := r.find(addr); h != nil {
+		h.onFailure()
+	}
+}
+
+func fetchNameServerAddrsFromHTTP(endpoint string) ([]string, error) {
+	resp, err := http.Get(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return splitNsAddrs(strings.TrimSpace(string(body))), nil
+}
+
+func resolveNameServerAddrsFromSRV(srvName string) ([]string, error) {
+	_, records, err := net.LookupSRV("", "", srvName)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs := make([]string, 0, len(records))
+	for _, rec := range records {
+		target := strings.TrimSuffix(rec.Target, ".")
+		addrs = append(addrs, target+":"+strconv.Itoa(int(rec.Port)))
+	}
+	return addrs, nil
+}
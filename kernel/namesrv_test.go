@@ -0,0 +1,124 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kernel
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNsAddrHealth_OnFailureBacksOffExponentially(t *testing.T) {
+	h := &nsAddrHealth{addr: "10.0.0.1:9876"}
+
+	if h.isBanned(time.Now()) {
+		t.Fatalf("a fresh address should not start out banned")
+	}
+
+	h.onFailure()
+	if !h.isBanned(time.Now()) {
+		t.Fatalf("address should be banned right after a failure")
+	}
+	firstBan := h.bannedUntil
+
+	h.onFailure()
+	if h.bannedUntil <= firstBan {
+		t.Errorf("a second consecutive failure should extend the ban further, got firstBan=%d secondBan=%d", firstBan, h.bannedUntil)
+	}
+
+	h.onSuccess()
+	if h.isBanned(time.Now()) {
+		t.Errorf("a success should clear the ban")
+	}
+	if h.failureCount != 0 {
+		t.Errorf("a success should reset the failure count, got %d", h.failureCount)
+	}
+}
+
+func TestNsAddrHealth_BackoffCapsAtMax(t *testing.T) {
+	h := &nsAddrHealth{addr: "10.0.0.1:9876"}
+
+	for i := 0; i < nsAddrMaxFailures+5; i++ {
+		h.onFailure()
+	}
+
+	until := time.Unix(0, h.bannedUntil)
+	if wait := until.Sub(time.Now()); wait > nsAddrMaxBackoff+time.Second {
+		t.Errorf("backoff should be capped at nsAddrMaxBackoff, got a ban lasting %s", wait)
+	}
+}
+
+func TestNameServerResolver_CandidatesOrdersBannedAddressesLast(t *testing.T) {
+	r := NewNameServerResolver("10.0.0.1:9876,10.0.0.2:9876,10.0.0.3:9876")
+
+	r.markFailure("10.0.0.2:9876")
+
+	candidates := r.candidates()
+	if len(candidates) != 3 {
+		t.Fatalf("expected all 3 known addresses to be returned, got %v", candidates)
+	}
+
+	bannedPos := -1
+	for i, addr := range candidates {
+		if addr == "10.0.0.2:9876" {
+			bannedPos = i
+		}
+	}
+	if bannedPos != len(candidates)-1 {
+		t.Errorf("banned address should be ordered last, got order %v", candidates)
+	}
+}
+
+func TestNameServerResolver_SetPollIntervalRejectsNonPositive(t *testing.T) {
+	r := NewNameServerResolver("")
+
+	r.SetPollInterval(5 * time.Second)
+	r.SetPollInterval(0)
+	r.SetPollInterval(-time.Second)
+
+	r.mutex.RLock()
+	interval := r.pollInterval
+	r.mutex.RUnlock()
+
+	if interval != 5*time.Second {
+		t.Errorf("non-positive intervals should be rejected, got pollInterval=%s", interval)
+	}
+}
+
+func TestNameServerResolver_ReplaceAddrsPreservesHealthState(t *testing.T) {
+	r := NewNameServerResolver("10.0.0.1:9876,10.0.0.2:9876")
+	r.markFailure("10.0.0.1:9876")
+
+	if r.find("10.0.0.1:9876").failureCount == 0 {
+		t.Fatalf("expected the failure to be recorded before the refresh")
+	}
+
+	// a refresh that returns the same address list should not reset
+	// previously recorded health state for addresses still present.
+	r.replaceAddrs([]string{"10.0.0.1:9876", "10.0.0.2:9876"})
+
+	if r.find("10.0.0.1:9876").failureCount == 0 {
+		t.Errorf("replaceAddrs should preserve health state for addresses that are still present")
+	}
+
+	// a newly discovered address should start out healthy, even if a
+	// different address was previously unhealthy.
+	r.replaceAddrs([]string{"10.0.0.1:9876", "10.0.0.3:9876"})
+	if r.find("10.0.0.3:9876").failureCount != 0 {
+		t.Errorf("a newly discovered address should start out healthy, got failureCount=%d", r.find("10.0.0.3:9876").failureCount)
+	}
+}
@@ -0,0 +1,210 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kernel
+
+import (
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// Message is the minimal message representation a QueueSelector needs to
+// pick a destination queue. ShardingKey is the key ordered/FIFO producers
+// supply so messages for the same key always land on the same queue.
+type Message struct {
+	Topic       string
+	Body        []byte
+	ShardingKey string
+}
+
+// QueueSelector picks the MessageQueue a message should be sent to out of
+// info's available queues. lastBrokerName is the broker the previous
+// attempt for this message was routed to (empty on the first attempt),
+// letting retry-aware selectors avoid repeating a broker that just failed.
+// This repo does not yet have a producer send path to plug a QueueSelector
+// into; wiring one in is left to whichever change introduces a producer.
+type QueueSelector interface {
+	SelectQueue(info *TopicPublishInfo, msg *Message, lastBrokerName string) *MessageQueue
+}
+
+// QueueSelectorRoundRobin cycles through info's queues in order. This is
+// the default strategy and matches TopicPublishInfo.fetchQueueIndex.
+type QueueSelectorRoundRobin struct{}
+
+func (QueueSelectorRoundRobin) SelectQueue(info *TopicPublishInfo, msg *Message, lastBrokerName string) *MessageQueue {
+	if !info.isOK() {
+		return nil
+	}
+	index := info.fetchQueueIndex()
+	if index < 0 {
+		return nil
+	}
+	return info.MqList[index]
+}
+
+// QueueSelectorHash routes a message to the queue selected by hashing
+// msg.ShardingKey, so messages sharing a key always land on the same
+// queue. Required for the OrderTopic path built by routeData2PublishInfo.
+type QueueSelectorHash struct{}
+
+func (QueueSelectorHash) SelectQueue(info *TopicPublishInfo, msg *Message, lastBrokerName string) *MessageQueue {
+	length := len(info.MqList)
+	if length == 0 {
+		return nil
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(msg.ShardingKey))
+	index := int(h.Sum32() % uint32(length))
+	return info.MqList[index]
+}
+
+// latencyMax and notAvailableDuration are paired buckets: a send observed
+// to take at least latencyMax[i] isolates its broker for
+// notAvailableDuration[i] milliseconds. Mirrors the Java client's
+// LatencyFaultToleranceImpl defaults.
+var (
+	latencyMax           = []int64{50, 100, 550, 1000, 2000, 3000, 15000}
+	notAvailableDuration = []int64{0, 0, 30000, 60000, 120000, 180000, 600000}
+)
+
+type faultItem struct {
+	brokerName     string
+	currentLatency int64
+	startTimestamp int64 // unix millis at which the broker is available again
+}
+
+func (item *faultItem) isAvailable(nowMillis int64) bool {
+	return nowMillis >= item.startTimestamp
+}
+
+// LatencyFaultTolerance records, per broker, the most recently observed
+// send latency and, after a failing send, how long that broker should be
+// isolated before being considered again.
+type LatencyFaultTolerance struct {
+	mutex      sync.RWMutex
+	faultItems map[string]*faultItem
+}
+
+// NewLatencyFaultTolerance creates an empty LatencyFaultTolerance; every
+// broker starts out available.
+func NewLatencyFaultTolerance() *LatencyFaultTolerance {
+	return &LatencyFaultTolerance{faultItems: make(map[string]*faultItem)}
+}
+
+// UpdateFaultItem records a send outcome for brokerName. A successful send
+// (isolation=false) isolates the broker for the duration bucketed from
+// currentLatency, which is zero below 550ms. A failing send
+// (isolation=true) always isolates the broker for the 10000ms bucket's
+// duration (180000ms); currentLatency is recorded but does not change
+// that duration.
+func (lft *LatencyFaultTolerance) UpdateFaultItem(brokerName string, currentLatency int64, isolation bool) {
+	duration := computeNotAvailableDuration(currentLatency)
+	if isolation {
+		duration = computeNotAvailableDuration(10000)
+	}
+
+	lft.mutex.Lock()
+	defer lft.mutex.Unlock()
+
+	item, exist := lft.faultItems[brokerName]
+	if !exist {
+		item = &faultItem{brokerName: brokerName}
+		lft.faultItems[brokerName] = item
+	}
+	item.currentLatency = currentLatency
+	item.startTimestamp = nowUnixMillis() + duration
+}
+
+func computeNotAvailableDuration(currentLatency int64) int64 {
+	for i := len(latencyMax) - 1; i >= 0; i-- {
+		if currentLatency >= latencyMax[i] {
+			return notAvailableDuration[i]
+		}
+	}
+	return 0
+}
+
+// IsAvailable reports whether brokerName is not currently isolated.
+func (lft *LatencyFaultTolerance) IsAvailable(brokerName string) bool {
+	lft.mutex.RLock()
+	defer lft.mutex.RUnlock()
+
+	item, exist := lft.faultItems[brokerName]
+	if !exist {
+		return true
+	}
+	return item.isAvailable(nowUnixMillis())
+}
+
+func nowUnixMillis() int64 {
+	return time.Now().UnixNano() / int64(time.Millisecond)
+}
+
+// QueueSelectorLatencyFaultTolerant prefers queues whose broker is not
+// currently isolated, falling back to an isolated broker only when every
+// candidate is unavailable so sends never stall outright.
+type QueueSelectorLatencyFaultTolerant struct {
+	faultTolerance *LatencyFaultTolerance
+}
+
+// NewQueueSelectorLatencyFaultTolerant builds a latency-aware selector
+// with its own LatencyFaultTolerance state.
+func NewQueueSelectorLatencyFaultTolerant() *QueueSelectorLatencyFaultTolerant {
+	return &QueueSelectorLatencyFaultTolerant{faultTolerance: NewLatencyFaultTolerance()}
+}
+
+func (s *QueueSelectorLatencyFaultTolerant) SelectQueue(info *TopicPublishInfo, msg *Message, lastBrokerName string) *MessageQueue {
+	length := len(info.MqList)
+	if length == 0 {
+		return nil
+	}
+
+	startIndex := info.fetchQueueIndex()
+	if startIndex < 0 {
+		return nil
+	}
+
+	for i := 0; i < length; i++ {
+		mq := info.MqList[(startIndex+i)%length]
+		if mq.BrokerName != lastBrokerName && s.faultTolerance.IsAvailable(mq.BrokerName) {
+			return mq
+		}
+	}
+
+	for i := 0; i < length; i++ {
+		mq := info.MqList[(startIndex+i)%length]
+		if s.faultTolerance.IsAvailable(mq.BrokerName) {
+			return mq
+		}
+	}
+
+	return info.MqList[startIndex%length]
+}
+
+// OnSendSuccess records a successful send so future selection can keep
+// favouring faster brokers.
+func (s *QueueSelectorLatencyFaultTolerant) OnSendSuccess(brokerName string, latency time.Duration) {
+	s.faultTolerance.UpdateFaultItem(brokerName, int64(latency/time.Millisecond), false)
+}
+
+// OnSendFailure quarantines brokerName for the fixed failure duration of
+// UpdateFaultItem (180000ms); the duration does not depend on latency.
+func (s *QueueSelectorLatencyFaultTolerant) OnSendFailure(brokerName string, latency time.Duration) {
+	s.faultTolerance.UpdateFaultItem(brokerName, int64(latency/time.Millisecond), true)
+}
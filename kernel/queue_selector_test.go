@@ -0,0 +1,169 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kernel
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func threeBrokerPublishInfo() *TopicPublishInfo {
+	return &TopicPublishInfo{
+		MqList: []*MessageQueue{
+			{Topic: "t", BrokerName: "broker-a", QueueId: 0},
+			{Topic: "t", BrokerName: "broker-b", QueueId: 0},
+			{Topic: "t", BrokerName: "broker-c", QueueId: 0},
+		},
+	}
+}
+
+func TestQueueSelectorRoundRobin_SpreadsEvenly(t *testing.T) {
+	info := threeBrokerPublishInfo()
+	selector := QueueSelectorRoundRobin{}
+
+	seen := make(map[string]int)
+	for i := 0; i < 6; i++ {
+		mq := selector.SelectQueue(info, &Message{}, "")
+		seen[mq.BrokerName]++
+	}
+
+	for _, brokerName := range []string{"broker-a", "broker-b", "broker-c"} {
+		if seen[brokerName] != 2 {
+			t.Errorf("expected each broker picked twice over 6 round-robin calls, got %v", seen)
+		}
+	}
+}
+
+func TestQueueSelectorHash_SameKeyAlwaysSameQueue(t *testing.T) {
+	info := threeBrokerPublishInfo()
+	selector := QueueSelectorHash{}
+
+	first := selector.SelectQueue(info, &Message{ShardingKey: "order-42"}, "")
+	for i := 0; i < 5; i++ {
+		mq := selector.SelectQueue(info, &Message{ShardingKey: "order-42"}, "")
+		if mq.BrokerName != first.BrokerName || mq.QueueId != first.QueueId {
+			t.Errorf("expected the same sharding key to route to the same queue every time, got %v then %v", first, mq)
+		}
+	}
+}
+
+func TestQueueSelectorHash_DifferentKeysCanLandOnDifferentQueues(t *testing.T) {
+	info := threeBrokerPublishInfo()
+	selector := QueueSelectorHash{}
+
+	seen := make(map[string]bool)
+	for i := 0; i < 50; i++ {
+		mq := selector.SelectQueue(info, &Message{ShardingKey: strconv.Itoa(i)}, "")
+		seen[mq.BrokerName] = true
+	}
+
+	if len(seen) < 2 {
+		t.Errorf("expected varied sharding keys to spread across more than one broker, got %v", seen)
+	}
+}
+
+func TestComputeNotAvailableDuration(t *testing.T) {
+	tests := []struct {
+		name    string
+		latency int64
+		want    int64
+	}{
+		{name: "below lowest bucket", latency: 10, want: 0},
+		{name: "exactly lowest bucket", latency: 50, want: 0},
+		{name: "mid bucket", latency: 600, want: 30000},
+		{name: "higher bucket", latency: 2500, want: 120000},
+		{name: "top bucket", latency: 20000, want: 600000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := computeNotAvailableDuration(tt.latency); got != tt.want {
+				t.Errorf("computeNotAvailableDuration(%d) = %d, want %d", tt.latency, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLatencyFaultTolerance_FailureIsolatesRegardlessOfLatency(t *testing.T) {
+	lft := NewLatencyFaultTolerance()
+
+	if !lft.IsAvailable("broker-a") {
+		t.Fatalf("an unknown broker should be available by default")
+	}
+
+	lft.UpdateFaultItem("broker-a", 10, true)
+	if lft.IsAvailable("broker-a") {
+		t.Errorf("a failing send should isolate the broker even when the observed latency was low")
+	}
+}
+
+func TestLatencyFaultTolerance_SuccessBelowLowestBucketStaysAvailable(t *testing.T) {
+	lft := NewLatencyFaultTolerance()
+
+	lft.UpdateFaultItem("broker-a", 10, false)
+	if !lft.IsAvailable("broker-a") {
+		t.Errorf("a successful send with latency below the lowest bucket should not isolate the broker")
+	}
+}
+
+func TestLatencyFaultTolerance_SuccessWithHighLatencyIsolates(t *testing.T) {
+	lft := NewLatencyFaultTolerance()
+
+	lft.UpdateFaultItem("broker-a", 2500, false)
+	if lft.IsAvailable("broker-a") {
+		t.Errorf("a successful send with high observed latency should still isolate the broker for its bucketed duration")
+	}
+}
+
+func TestQueueSelectorLatencyFaultTolerant_SkipsIsolatedBrokers(t *testing.T) {
+	info := threeBrokerPublishInfo()
+	selector := NewQueueSelectorLatencyFaultTolerant()
+
+	selector.OnSendFailure("broker-a", 600*time.Millisecond)
+	selector.OnSendFailure("broker-b", 600*time.Millisecond)
+
+	mq := selector.SelectQueue(info, &Message{}, "")
+	if mq.BrokerName != "broker-c" {
+		t.Errorf("expected the only non-isolated broker (broker-c) to be picked, got %s", mq.BrokerName)
+	}
+}
+
+func TestQueueSelectorLatencyFaultTolerant_AvoidsLastBrokerWhenAlternativeAvailable(t *testing.T) {
+	info := threeBrokerPublishInfo()
+	selector := NewQueueSelectorLatencyFaultTolerant()
+
+	mq := selector.SelectQueue(info, &Message{}, "broker-a")
+	if mq.BrokerName == "broker-a" {
+		t.Errorf("expected the selector to avoid the last broker when another is available, got %s", mq.BrokerName)
+	}
+}
+
+func TestQueueSelectorLatencyFaultTolerant_FallsBackWhenEveryBrokerIsolated(t *testing.T) {
+	info := threeBrokerPublishInfo()
+	selector := NewQueueSelectorLatencyFaultTolerant()
+
+	for _, mq := range info.MqList {
+		selector.OnSendFailure(mq.BrokerName, 600*time.Millisecond)
+	}
+
+	mq := selector.SelectQueue(info, &Message{}, "")
+	if mq == nil {
+		t.Errorf("expected a queue to still be returned even when every broker is isolated, to avoid stalling sends outright")
+	}
+}
@@ -26,7 +26,6 @@ import (
 	"sort"
 	"strconv"
 	"strings"
-	"sync"
 	"sync/atomic"
 	"time"
 )
@@ -42,18 +41,6 @@ var (
 	ErrTopicNotExist = errors.New("topic not exist")
 )
 
-var (
-	// brokerName -> *BrokerData
-	brokerAddressesMap sync.Map
-
-	// brokerName -> map[string]int32
-	brokerVersionMap sync.Map
-
-	publishInfoMap sync.Map
-	routeDataMap   sync.Map
-	lockNamesrv    sync.Mutex
-)
-
 // key is topic, value is TopicPublishInfo
 type TopicPublishInfo struct {
 	OrderTopic          bool
@@ -76,12 +63,12 @@ func (info *TopicPublishInfo) fetchQueueIndex() int {
 	return int(qIndex) % length
 }
 
-func UpdateTopicRouteInfo(topic string) {
+func (c *MQClientInstance) UpdateTopicRouteInfo(topic string) {
 	// Todo process lock timeout
-	lockNamesrv.Lock()
-	defer lockNamesrv.Unlock()
+	c.lockNamesrv.Lock()
+	defer c.lockNamesrv.Unlock()
 
-	routeData, err := queryTopicRouteInfoFromServer(topic, requestTimeout)
+	routeData, err := c.queryTopicRouteInfoFromServer(topic, requestTimeout)
 	if err != nil {
 		rlog.Warnf("query topic route from server error: %s", err)
 		return
@@ -93,7 +80,7 @@ func UpdateTopicRouteInfo(topic string) {
 	}
 
 	var changed bool
-	oldRouteData, exist := routeDataMap.Load(topic)
+	oldRouteData, exist := c.routeDataMap.Load(topic)
 	if !exist || routeData == nil {
 		changed = true
 	} else {
@@ -101,7 +88,7 @@ func UpdateTopicRouteInfo(topic string) {
 	}
 
 	if !changed {
-		changed = isNeedUpdateTopicRouteInfo(topic)
+		changed = c.isNeedUpdateTopicRouteInfo(topic)
 	} else {
 		rlog.Infof("the topic[%s] route info changed, old[%v] ,new[%s]", topic, oldRouteData, routeData)
 	}
@@ -113,38 +100,57 @@ func UpdateTopicRouteInfo(topic string) {
 	newTopicRouteData := routeData.clone()
 
 	for _, brokerData := range newTopicRouteData.BrokerDataList {
-		brokerAddressesMap.Store(brokerData.BrokerName, brokerData.BrokerAddresses)
+		c.brokerAddressesMap.Store(brokerData.BrokerName, brokerData.BrokerAddresses)
 	}
 
 	// update publish info
 	publishInfo := routeData2PublishInfo(topic, routeData)
 	publishInfo.HaveTopicRouterInfo = true
 
-	old, _ := publishInfoMap.Load(topic)
-	publishInfoMap.Store(topic, publishInfoMap)
+	old, _ := c.publishInfoMap.Load(topic)
+	c.publishInfoMap.Store(topic, publishInfo)
+	c.routeDataMap.Store(topic, routeData)
 	if old != nil {
 		rlog.Infof("Old TopicPublishInfo [%s] removed.", old)
 	}
 }
 
-func FindBrokerAddressInPublish(brokerName string) string {
-	bd, exist := brokerAddressesMap.Load(brokerName)
+// FindBrokerAddressInPublish looks up a publish-capable broker address,
+// returning its VIP channel form when vipChannelEnabled is true and that
+// broker's VIP channel is not in its failure cooldown. vipChannelEnabled
+// is a per-call argument rather than a field on MQClientInstance because
+// a producer and a consumer sharing the same instance (same nameserver
+// address and unit name) need different defaults; see
+// DefaultProducerVipChannelEnabled and DefaultConsumerVipChannelEnabled.
+func (c *MQClientInstance) FindBrokerAddressInPublish(brokerName string, vipChannelEnabled bool) string {
+	bd, exist := c.brokerAddressesMap.Load(brokerName)
 
 	if !exist {
 		return ""
 	}
 
-	return bd.(*BrokerData).BrokerAddresses[MasterId]
+	addr := bd.(map[int64]string)[MasterId]
+	if addr == "" {
+		return ""
+	}
+
+	if vipChannelEnabled && c.vipAvailable(brokerName) {
+		if vipAddr, err := brokerVipAddr(addr); err == nil {
+			return vipAddr
+		}
+	}
+
+	return addr
 }
 
-func FindBrokerAddressInSubscribe(brokerName string, brokerId int64, onlyThisBroker bool) *FindBrokerResult {
+func (c *MQClientInstance) FindBrokerAddressInSubscribe(brokerName string, brokerId int64, onlyThisBroker bool) *FindBrokerResult {
 	var (
 		brokerAddr = ""
 		slave      = false
 		found      = false
 	)
 
-	addrs, exist := brokerAddressesMap.Load(brokerName)
+	addrs, exist := c.brokerAddressesMap.Load(brokerName)
 
 	if exist {
 		for k, v := range addrs.(map[int64]string) {
@@ -164,15 +170,15 @@ func FindBrokerAddressInSubscribe(brokerName string, brokerId int64, onlyThisBro
 		result = &FindBrokerResult{
 			BrokerAddr:    brokerAddr,
 			Slave:         slave,
-			BrokerVersion: findBrokerVersion(brokerName, brokerAddr),
+			BrokerVersion: c.findBrokerVersion(brokerName, brokerAddr),
 		}
 	}
 
 	return result
 }
 
-func FetchSubscribeMessageQueues(topic string) ([]*MessageQueue, error) {
-	routeData, err := queryTopicRouteInfoFromServer(topic, 3*time.Second)
+func (c *MQClientInstance) FetchSubscribeMessageQueues(topic string) ([]*MessageQueue, error) {
+	routeData, err := c.queryTopicRouteInfoFromServer(topic, 3*time.Second)
 
 	if err != nil {
 		return nil, err
@@ -190,8 +196,8 @@ func FetchSubscribeMessageQueues(topic string) ([]*MessageQueue, error) {
 	return mqs, nil
 }
 
-func findBrokerVersion(brokerName, brokerAddr string) int {
-	versions, exist := brokerVersionMap.Load(brokerName)
+func (c *MQClientInstance) findBrokerVersion(brokerName, brokerAddr string) int {
+	versions, exist := c.brokerVersionMap.Load(brokerName)
 
 	if !exist {
 		return 0
@@ -205,35 +211,48 @@ func findBrokerVersion(brokerName, brokerAddr string) int {
 	return 0
 }
 
-func queryTopicRouteInfoFromServer(topic string, timeout time.Duration) (*topicRouteData, error) {
+func (c *MQClientInstance) queryTopicRouteInfoFromServer(topic string, timeout time.Duration) (*topicRouteData, error) {
 	request := &GetRouteInfoRequest{
 		Topic: topic,
 	}
 	rc := remote.NewRemotingCommand(ReqGetRouteInfoByTopic, request, nil)
-	response, err := remote.InvokeSync(getNameServerAddress(), rc, timeout)
 
-	if err != nil {
-		return nil, err
+	candidates := c.resolver.candidates()
+	if len(candidates) == 0 {
+		return nil, errors.New("no available name server address")
 	}
 
-	switch response.Code {
-	case ResSuccess:
-		if response.Body == nil {
-			return nil, errors.New(response.Remark)
+	var lastErr error
+	for _, addr := range candidates {
+		response, err := remote.InvokeSync(addr, rc, timeout)
+		if err != nil {
+			c.resolver.markFailure(addr)
+			lastErr = err
+			continue
 		}
-		routeData := &topicRouteData{}
+		c.resolver.markSuccess(addr)
 
-		err = routeData.decode(string(response.Body))
-		if err != nil {
-			rlog.Warnf("decode topicRouteData error: %s", err)
-			return nil, err
+		switch response.Code {
+		case ResSuccess:
+			if response.Body == nil {
+				return nil, errors.New(response.Remark)
+			}
+			routeData := &topicRouteData{}
+
+			err = routeData.decode(string(response.Body))
+			if err != nil {
+				rlog.Warnf("decode topicRouteData error: %s", err)
+				return nil, err
+			}
+			return routeData, nil
+		case ResTopicNotExist:
+			return nil, ErrTopicNotExist
+		default:
+			lastErr = errors.New(response.Remark)
 		}
-		return routeData, nil
-	case ResTopicNotExist:
-		return nil, ErrTopicNotExist
-	default:
-		return nil, errors.New(response.Remark)
 	}
+
+	return nil, lastErr
 }
 
 func topicRouteDataIsChange(oldData *topicRouteData, newData *topicRouteData) bool {
@@ -259,8 +278,8 @@ func topicRouteDataIsChange(oldData *topicRouteData, newData *topicRouteData) bo
 	return !oldDataCloned.equals(newDataCloned)
 }
 
-func isNeedUpdateTopicRouteInfo(topic string) bool {
-	value, exist := publishInfoMap.Load(topic)
+func (c *MQClientInstance) isNeedUpdateTopicRouteInfo(topic string) bool {
+	value, exist := c.publishInfoMap.Load(topic)
 
 	return !exist || value.(*TopicPublishInfo).isOK()
 }
@@ -292,7 +311,7 @@ func routeData2PublishInfo(topic string, data *topicRouteData) *TopicPublishInfo
 
 	qds := data.QueueDataList
 	sort.Slice(qds, func(i, j int) bool {
-		return i-j >= 0
+		return qds[i].BrokerName < qds[j].BrokerName
 	})
 
 	for _, qd := range qds {
@@ -325,10 +344,6 @@ func routeData2PublishInfo(topic string, data *topicRouteData) *TopicPublishInfo
 	return publishInfo
 }
 
-func getNameServerAddress() string {
-	return "127.0.0.1:9876"
-}
-
 // topicRouteData topicRouteData
 type topicRouteData struct {
 	OrderTopicConf string
@@ -384,7 +399,36 @@ func (routeData *topicRouteData) clone() *topicRouteData {
 }
 
 func (routeData *topicRouteData) equals(data *topicRouteData) bool {
-	return false
+	if routeData == data {
+		return true
+	}
+	if routeData == nil || data == nil {
+		return false
+	}
+
+	if routeData.OrderTopicConf != data.OrderTopicConf {
+		return false
+	}
+
+	if len(routeData.QueueDataList) != len(data.QueueDataList) {
+		return false
+	}
+	for i := range routeData.QueueDataList {
+		if *routeData.QueueDataList[i] != *data.QueueDataList[i] {
+			return false
+		}
+	}
+
+	if len(routeData.BrokerDataList) != len(data.BrokerDataList) {
+		return false
+	}
+	for i := range routeData.BrokerDataList {
+		if !routeData.BrokerDataList[i].equals(data.BrokerDataList[i]) {
+			return false
+		}
+	}
+
+	return true
 }
 
 // QueueData QueueData
@@ -398,8 +442,34 @@ type QueueData struct {
 
 // BrokerData BrokerData
 type BrokerData struct {
-	Cluster             string           `json:"cluster"`
-	BrokerName          string           `json:"brokerName"`
-	BrokerAddresses     map[int64]string `json:"brokerAddrs"`
-	brokerAddressesLock sync.RWMutex
+	Cluster         string           `json:"cluster"`
+	BrokerName      string           `json:"brokerName"`
+	BrokerAddresses map[int64]string `json:"brokerAddrs"`
+}
+
+// equals reports whether bd and other describe the same broker, including
+// a deep comparison of BrokerAddresses. BrokerAddresses is a map so it
+// cannot be compared with ==.
+func (bd *BrokerData) equals(other *BrokerData) bool {
+	if bd == other {
+		return true
+	}
+	if bd == nil || other == nil {
+		return false
+	}
+
+	if bd.BrokerName != other.BrokerName || bd.Cluster != other.Cluster {
+		return false
+	}
+
+	if len(bd.BrokerAddresses) != len(other.BrokerAddresses) {
+		return false
+	}
+	for id, addr := range bd.BrokerAddresses {
+		if otherAddr, ok := other.BrokerAddresses[id]; !ok || otherAddr != addr {
+			return false
+		}
+	}
+
+	return true
 }
@@ -0,0 +1,185 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kernel
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/apache/rocketmq-client-go/rlog"
+)
+
+const defaultRouteRefreshInterval = 30 * time.Second
+
+// RouteManager owns a background goroutine that periodically re-queries
+// the nameserver for every topic a producer or consumer has registered
+// interest in, and prunes broker addresses that no longer appear in any
+// topic's route once a refresh pass completes. Producers and consumers
+// should register the topics they use through RegisterTopic instead of
+// calling UpdateTopicRouteInfo directly. This repo does not yet have a
+// producer or consumer implementation to call RegisterTopic/UnregisterTopic
+// from; wiring that up is left to whichever change introduces them.
+type RouteManager struct {
+	mutex  sync.Mutex
+	topics map[string]int // topic -> reference count
+
+	interval  time.Duration
+	instance  *MQClientInstance
+	queryFunc func(topic string)
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewRouteManager builds a RouteManager that refreshes instance's routes
+// every defaultRouteRefreshInterval once started.
+func NewRouteManager(instance *MQClientInstance) *RouteManager {
+	return &RouteManager{
+		topics:    make(map[string]int),
+		interval:  defaultRouteRefreshInterval,
+		instance:  instance,
+		queryFunc: instance.UpdateTopicRouteInfo,
+	}
+}
+
+// Start launches the background refresh goroutine, stopping it when ctx
+// is done or Stop is called. Calling Start while already running is a
+// no-op.
+func (rm *RouteManager) Start(ctx context.Context) {
+	rm.mutex.Lock()
+	if rm.cancel != nil {
+		rm.mutex.Unlock()
+		return
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	rm.cancel = cancel
+	rm.done = make(chan struct{})
+	rm.mutex.Unlock()
+
+	go rm.loop(ctx)
+}
+
+func (rm *RouteManager) loop(ctx context.Context) {
+	defer close(rm.done)
+
+	ticker := time.NewTicker(rm.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			rm.refreshAll()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (rm *RouteManager) refreshAll() {
+	for _, topic := range rm.registeredTopics() {
+		rm.queryFunc(topic)
+	}
+	rm.cleanOfflineBroker()
+}
+
+func (rm *RouteManager) registeredTopics() []string {
+	rm.mutex.Lock()
+	defer rm.mutex.Unlock()
+
+	topics := make([]string, 0, len(rm.topics))
+	for topic := range rm.topics {
+		topics = append(topics, topic)
+	}
+	return topics
+}
+
+// Stop terminates the background refresh goroutine and waits for it to
+// exit. It is safe to call Stop on a RouteManager that was never
+// started.
+func (rm *RouteManager) Stop() {
+	rm.mutex.Lock()
+	cancel := rm.cancel
+	done := rm.done
+	rm.cancel = nil
+	rm.mutex.Unlock()
+
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+}
+
+// RegisterTopic records that a producer or consumer depends on topic, so
+// it is included in the periodic route refresh. Safe to call repeatedly
+// and concurrently for the same topic; each call must be matched with an
+// UnregisterTopic once the caller is done with the topic.
+func (rm *RouteManager) RegisterTopic(topic string) {
+	rm.mutex.Lock()
+	rm.topics[topic]++
+	rm.mutex.Unlock()
+}
+
+// UnregisterTopic removes a single interest in topic previously recorded
+// by RegisterTopic. The topic stops being refreshed once its reference
+// count drops to zero.
+func (rm *RouteManager) UnregisterTopic(topic string) {
+	rm.mutex.Lock()
+	if count, ok := rm.topics[topic]; ok {
+		if count <= 1 {
+			delete(rm.topics, topic)
+		} else {
+			rm.topics[topic] = count - 1
+		}
+	}
+	rm.mutex.Unlock()
+}
+
+// cleanOfflineBroker drops any broker address from the instance's
+// brokerAddressesMap that is no longer referenced by any topic's current
+// route, mirroring the Java client's behaviour of pruning brokers once
+// they disappear from every topic route.
+func (rm *RouteManager) cleanOfflineBroker() {
+	liveBrokers := make(map[string]bool)
+
+	rm.instance.publishInfoMap.Range(func(key, value interface{}) bool {
+		info, ok := value.(*TopicPublishInfo)
+		if !ok || info.RouteData == nil {
+			return true
+		}
+		for _, bd := range info.RouteData.BrokerDataList {
+			liveBrokers[bd.BrokerName] = true
+		}
+		return true
+	})
+
+	var offline []string
+	rm.instance.brokerAddressesMap.Range(func(key, value interface{}) bool {
+		brokerName, _ := key.(string)
+		if !liveBrokers[brokerName] {
+			offline = append(offline, brokerName)
+		}
+		return true
+	})
+
+	for _, brokerName := range offline {
+		rm.instance.brokerAddressesMap.Delete(brokerName)
+		rlog.Infof("broker [%s] no longer present in any topic route, removed", brokerName)
+	}
+}
@@ -0,0 +1,85 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kernel
+
+import "testing"
+
+func TestRouteManager_CleanOfflineBroker(t *testing.T) {
+	instance := &MQClientInstance{}
+
+	instance.brokerAddressesMap.Store("broker-a", map[int64]string{MasterId: "10.0.0.1:10911"})
+	instance.brokerAddressesMap.Store("broker-b", map[int64]string{MasterId: "10.0.0.2:10911"})
+
+	instance.publishInfoMap.Store("topic-a", &TopicPublishInfo{
+		RouteData: &topicRouteData{
+			BrokerDataList: []*BrokerData{{BrokerName: "broker-a"}},
+		},
+	})
+
+	rm := NewRouteManager(instance)
+	rm.cleanOfflineBroker()
+
+	if _, ok := instance.brokerAddressesMap.Load("broker-a"); !ok {
+		t.Errorf("broker-a is still referenced by topic-a and should not have been removed")
+	}
+	if _, ok := instance.brokerAddressesMap.Load("broker-b"); ok {
+		t.Errorf("broker-b is no longer referenced by any topic and should have been removed")
+	}
+}
+
+func TestRouteManager_RegisterUnregisterTopic(t *testing.T) {
+	rm := NewRouteManager(&MQClientInstance{})
+	rm.RegisterTopic("topic-a")
+	rm.RegisterTopic("topic-a")
+	rm.UnregisterTopic("topic-a")
+
+	topics := rm.registeredTopics()
+	if len(topics) != 1 || topics[0] != "topic-a" {
+		t.Errorf("expected topic-a to still be registered once, got %v", topics)
+	}
+
+	rm.UnregisterTopic("topic-a")
+	if topics := rm.registeredTopics(); len(topics) != 0 {
+		t.Errorf("expected no topics registered, got %v", topics)
+	}
+}
+
+func TestRouteManager_RefreshAllUsesFakeNameserver(t *testing.T) {
+	instance := &MQClientInstance{}
+	instance.brokerAddressesMap.Store("broker-a", map[int64]string{MasterId: "10.0.0.1:10911"})
+
+	var queried []string
+	rm := NewRouteManager(instance)
+	rm.queryFunc = func(topic string) {
+		queried = append(queried, topic)
+		// fake nameserver response: topic-a's route no longer mentions broker-a
+		instance.publishInfoMap.Store(topic, &TopicPublishInfo{
+			RouteData: &topicRouteData{},
+		})
+	}
+	rm.RegisterTopic("topic-a")
+
+	rm.refreshAll()
+
+	if len(queried) != 1 || queried[0] != "topic-a" {
+		t.Errorf("expected fake nameserver to be queried for topic-a, got %v", queried)
+	}
+	if _, ok := instance.brokerAddressesMap.Load("broker-a"); ok {
+		t.Errorf("broker-a should have been cleaned up once topic-a's route stopped referencing it")
+	}
+}
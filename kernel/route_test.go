@@ -0,0 +1,152 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kernel
+
+import (
+	"sort"
+	"testing"
+)
+
+func baseRouteData() *topicRouteData {
+	return &topicRouteData{
+		OrderTopicConf: "",
+		QueueDataList: []*QueueData{
+			{BrokerName: "broker-a", ReadQueueNums: 4, WriteQueueNums: 4, Perm: 6, TopicSynFlag: 0},
+			{BrokerName: "broker-b", ReadQueueNums: 4, WriteQueueNums: 4, Perm: 6, TopicSynFlag: 0},
+		},
+		BrokerDataList: []*BrokerData{
+			{BrokerName: "broker-a", Cluster: "cluster-1", BrokerAddresses: map[int64]string{MasterId: "10.0.0.1:10911"}},
+			{BrokerName: "broker-b", Cluster: "cluster-1", BrokerAddresses: map[int64]string{MasterId: "10.0.0.2:10911"}},
+		},
+	}
+}
+
+func TestTopicRouteData_Equals(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(data *topicRouteData)
+		wantEq bool
+	}{
+		{
+			name:   "identical",
+			mutate: func(data *topicRouteData) {},
+			wantEq: true,
+		},
+		{
+			name: "reordered broker and queue lists still equal",
+			mutate: func(data *topicRouteData) {
+				data.QueueDataList[0], data.QueueDataList[1] = data.QueueDataList[1], data.QueueDataList[0]
+				data.BrokerDataList[0], data.BrokerDataList[1] = data.BrokerDataList[1], data.BrokerDataList[0]
+			},
+			wantEq: true,
+		},
+		{
+			name: "broker added",
+			mutate: func(data *topicRouteData) {
+				data.BrokerDataList = append(data.BrokerDataList, &BrokerData{
+					BrokerName:      "broker-c",
+					Cluster:         "cluster-1",
+					BrokerAddresses: map[int64]string{MasterId: "10.0.0.3:10911"},
+				})
+			},
+			wantEq: false,
+		},
+		{
+			name: "broker removed",
+			mutate: func(data *topicRouteData) {
+				data.BrokerDataList = data.BrokerDataList[:1]
+			},
+			wantEq: false,
+		},
+		{
+			name: "permission changed",
+			mutate: func(data *topicRouteData) {
+				data.QueueDataList[0].Perm = 2
+			},
+			wantEq: false,
+		},
+		{
+			name: "broker address map mutated",
+			mutate: func(data *topicRouteData) {
+				data.BrokerDataList[0].BrokerAddresses[MasterId] = "10.0.0.99:10911"
+			},
+			wantEq: false,
+		},
+		{
+			name: "broker address map grown",
+			mutate: func(data *topicRouteData) {
+				data.BrokerDataList[0].BrokerAddresses[1] = "10.0.0.1:10912"
+			},
+			wantEq: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := baseRouteData()
+			mutated := baseRouteData()
+			tt.mutate(mutated)
+
+			// sort both by BrokerName before comparing, as
+			// topicRouteDataIsChange does (it sorts in descending order),
+			// so reordering alone is not mistaken for a real change.
+			sortRouteDataForComparison(original)
+			sortRouteDataForComparison(mutated)
+
+			if got := original.equals(mutated); got != tt.wantEq {
+				t.Errorf("equals() = %v, want %v", got, tt.wantEq)
+			}
+		})
+	}
+}
+
+func sortRouteDataForComparison(data *topicRouteData) {
+	sort.Slice(data.QueueDataList, func(i, j int) bool {
+		return data.QueueDataList[i].BrokerName < data.QueueDataList[j].BrokerName
+	})
+	sort.Slice(data.BrokerDataList, func(i, j int) bool {
+		return data.BrokerDataList[i].BrokerName < data.BrokerDataList[j].BrokerName
+	})
+}
+
+func TestRouteData2PublishInfo_SortsQueuesByBrokerName(t *testing.T) {
+	data := &topicRouteData{
+		QueueDataList: []*QueueData{
+			{BrokerName: "broker-z", WriteQueueNums: 1, Perm: 6},
+			{BrokerName: "broker-a", WriteQueueNums: 1, Perm: 6},
+			{BrokerName: "broker-m", WriteQueueNums: 1, Perm: 6},
+		},
+		BrokerDataList: []*BrokerData{
+			{BrokerName: "broker-z", BrokerAddresses: map[int64]string{MasterId: "10.0.0.3:10911"}},
+			{BrokerName: "broker-a", BrokerAddresses: map[int64]string{MasterId: "10.0.0.1:10911"}},
+			{BrokerName: "broker-m", BrokerAddresses: map[int64]string{MasterId: "10.0.0.2:10911"}},
+		},
+	}
+
+	publishInfo := routeData2PublishInfo("test-topic", data)
+
+	want := []string{"broker-a", "broker-m", "broker-z"}
+	if len(publishInfo.MqList) != len(want) {
+		t.Fatalf("expected %d queues, got %d", len(want), len(publishInfo.MqList))
+	}
+	for i, brokerName := range want {
+		if publishInfo.MqList[i].BrokerName != brokerName {
+			t.Errorf("MqList[%d].BrokerName = %s, want %s", i, publishInfo.MqList[i].BrokerName, brokerName)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kernel
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// vipCooldown is how long a broker's VIP channel is skipped after a send
+// to it returns a connection error, so a misconfigured broker doesn't
+// cause permanent send failures.
+const vipCooldown = 3 * time.Minute
+
+// DefaultProducerVipChannelEnabled and DefaultConsumerVipChannelEnabled are
+// the conventional vipChannelEnabled defaults for each role, matching the
+// Java client: producers use the VIP channel by default since it is
+// reserved for sends, while consumers default to the normal broker address.
+// A producer and a consumer can share one MQClientInstance (same
+// NameServerAddr/UnitName), so this can't be a field on the instance — each
+// caller passes its own value into FindBrokerAddressInPublish.
+const (
+	DefaultProducerVipChannelEnabled = true
+	DefaultConsumerVipChannelEnabled = false
+)
+
+// brokerVipAddr returns the VIP-channel form of a broker address. The
+// broker listens on port-2 for a dedicated producer send socket that
+// bypasses consumer traffic contention, matching the Java client.
+func brokerVipAddr(addr string) (string, error) {
+	i := strings.LastIndex(addr, ":")
+	if i < 0 {
+		return "", fmt.Errorf("invalid broker address: %s", addr)
+	}
+
+	port, err := strconv.Atoi(addr[i+1:])
+	if err != nil {
+		return "", fmt.Errorf("invalid broker address: %s", addr)
+	}
+
+	return addr[:i] + ":" + strconv.Itoa(port-2), nil
+}
+
+// vipAvailable reports whether brokerName's VIP channel is not currently
+// in its post-failure cooldown.
+func (c *MQClientInstance) vipAvailable(brokerName string) bool {
+	until, exist := c.vipUnavailableMap.Load(brokerName)
+	if !exist {
+		return true
+	}
+	return time.Now().UnixNano() >= until.(int64)
+}
+
+// MarkVipUnavailable quarantines brokerName's VIP channel for vipCooldown
+// after a connection error sending to its VIP address; the normal broker
+// address is used for subsequent publishes until the cooldown elapses.
+func (c *MQClientInstance) MarkVipUnavailable(brokerName string) {
+	c.vipUnavailableMap.Store(brokerName, time.Now().Add(vipCooldown).UnixNano())
+}
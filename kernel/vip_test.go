@@ -0,0 +1,70 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kernel
+
+import "testing"
+
+func TestBrokerVipAddr(t *testing.T) {
+	tests := []struct {
+		name    string
+		addr    string
+		want    string
+		wantErr bool
+	}{
+		{name: "normal port", addr: "10.0.0.1:10911", want: "10.0.0.1:10909"},
+		{name: "no port", addr: "10.0.0.1", wantErr: true},
+		{name: "non-numeric port", addr: "10.0.0.1:abc", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := brokerVipAddr(tt.addr)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("brokerVipAddr(%q) expected an error, got %q", tt.addr, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("brokerVipAddr(%q) unexpected error: %s", tt.addr, err)
+			}
+			if got != tt.want {
+				t.Errorf("brokerVipAddr(%q) = %q, want %q", tt.addr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindBrokerAddressInPublish_VipChannel(t *testing.T) {
+	instance := &MQClientInstance{}
+	instance.brokerAddressesMap.Store("broker-a", map[int64]string{MasterId: "10.0.0.1:10911"})
+
+	if got := instance.FindBrokerAddressInPublish("broker-a", true); got != "10.0.0.1:10909" {
+		t.Errorf("expected VIP address, got %q", got)
+	}
+
+	instance.MarkVipUnavailable("broker-a")
+
+	if got := instance.FindBrokerAddressInPublish("broker-a", true); got != "10.0.0.1:10911" {
+		t.Errorf("expected normal address after VIP marked unavailable, got %q", got)
+	}
+
+	if got := instance.FindBrokerAddressInPublish("broker-a", false); got != "10.0.0.1:10911" {
+		t.Errorf("expected normal address when vipChannelEnabled is false, got %q", got)
+	}
+}